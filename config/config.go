@@ -0,0 +1,36 @@
+// Package config defines the on-disk configuration shape for the gateway,
+// loaded once at startup and passed down to each service constructor.
+package config
+
+import "time"
+
+// ServicesConfig is the root "services" config section, one field per
+// upstream service this gateway proxies widgets for.
+type ServicesConfig struct {
+	// Gotify is keyed by instance name, so the gateway can fan requests out
+	// across more than one Gotify server and return an aggregated view
+	// alongside each instance's own.
+	Gotify map[string]GotifyInstanceConfig `yaml:"gotify"`
+}
+
+// GotifyInstanceConfig configures a single named Gotify instance.
+type GotifyInstanceConfig struct {
+	Url string `yaml:"url"`
+	Key string `yaml:"key"`
+
+	// CacheTTL is how long a successful response from this instance is
+	// reused before being re-fetched. Zero disables caching.
+	CacheTTL time.Duration `yaml:"cacheTtl"`
+	// RetryCount is how many additional attempts are made after a failed
+	// request to this instance. Zero disables retries.
+	RetryCount int `yaml:"retryCount"`
+	// RetryBackoff is the base delay for the jittered exponential backoff
+	// between retries; the max backoff is derived from it.
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// ConcurrencyLimit caps the number of requests in flight to this
+	// instance at once. Zero disables the limit.
+	ConcurrencyLimit int `yaml:"concurrencyLimit"`
+	// StatePath, if set, persists this instance's message high-water mark
+	// to disk so it survives a restart.
+	StatePath string `yaml:"statePath"`
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"homepage-widgets-gateway/internal/services"
+	"net/http"
+)
+
+// GotifyHandler serves the Gotify widget endpoints: a single-instance view
+// via the {instance} path parameter, and an aggregated view across every
+// configured instance when no instance is given.
+type GotifyHandler struct {
+	gotify services.GotifyService
+}
+
+func NewGotifyHandler(gotify services.GotifyService) *GotifyHandler {
+	return &GotifyHandler{gotify: gotify}
+}
+
+// ServeMessages handles GET /gotify/{instance}/messages and GET
+// /gotify/messages (aggregated across all instances).
+func (h *GotifyHandler) ServeMessages(w http.ResponseWriter, r *http.Request) {
+	instance := r.PathValue("instance")
+	if instance == "" {
+		breakdown, err := h.gotify.GetMessages()
+		writeJSON(w, breakdown, err)
+		return
+	}
+
+	response, err := h.gotify.GetMessagesForInstance(instance)
+	writeJSON(w, response, err)
+}
+
+// ServeApplications handles GET /gotify/{instance}/applications and GET
+// /gotify/applications (aggregated across all instances).
+func (h *GotifyHandler) ServeApplications(w http.ResponseWriter, r *http.Request) {
+	instance := r.PathValue("instance")
+	if instance == "" {
+		breakdown, err := h.gotify.GetApplications()
+		writeJSON(w, breakdown, err)
+		return
+	}
+
+	response, err := h.gotify.GetApplicationsForInstance(instance)
+	writeJSON(w, response, err)
+}
+
+// ServeClients handles GET /gotify/{instance}/clients and GET
+// /gotify/clients (aggregated across all instances).
+func (h *GotifyHandler) ServeClients(w http.ResponseWriter, r *http.Request) {
+	instance := r.PathValue("instance")
+	if instance == "" {
+		breakdown, err := h.gotify.GetClients()
+		writeJSON(w, breakdown, err)
+		return
+	}
+
+	response, err := h.gotify.GetClientsForInstance(instance)
+	writeJSON(w, response, err)
+}
+
+// errorBody is the structured JSON body rendered for any service error that
+// carries a stable errHTTP code, so the frontend and operators can
+// distinguish "gotify is down" from "token is wrong" from "we can't parse
+// the response" instead of an opaque 500.
+type errorBody struct {
+	Code  int    `json:"code"`
+	HTTP  int    `json:"http"`
+	Error string `json:"error"`
+	Link  string `json:"link,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}, err error) {
+	if err != nil {
+		if herr, ok := services.AsHTTPError(err); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(herr.HTTPStatus)
+			_ = json.NewEncoder(w).Encode(errorBody{
+				Code:  herr.Code,
+				HTTP:  herr.HTTPStatus,
+				Error: herr.Message,
+				Link:  herr.Link,
+			})
+			return
+		}
+
+		http.Error(w, fmt.Sprintf("failed to fetch gotify data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"homepage-widgets-gateway/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// sinceFromQuery parses the `since` query parameter used to backfill
+// messages missed while a client was disconnected. An invalid or missing
+// value means "no backfill".
+func sinceFromQuery(r *http.Request) int64 {
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return since
+}
+
+var gotifyStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Widgets are embedded on dashboards served from arbitrary origins.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// GotifyStreamHandler serves push updates for Gotify messages over WebSocket
+// and SSE, replacing the polling `GetMessages` loop for live dashboards.
+type GotifyStreamHandler struct {
+	gotify services.GotifyService
+}
+
+func NewGotifyStreamHandler(gotify services.GotifyService) *GotifyStreamHandler {
+	return &GotifyStreamHandler{gotify: gotify}
+}
+
+// ServeWS handles GET /gotify/{instance}/stream/ws?since=<msgID>.
+func (h *GotifyStreamHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	messages, err := h.gotify.SubscribeMessages(r.Context(), r.PathValue("instance"), sinceFromQuery(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to subscribe to gotify messages: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	conn, err := gotifyStreamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for msg := range messages {
+		if err = conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// ServeSSE handles GET /gotify/{instance}/stream/sse?since=<msgID>.
+func (h *GotifyStreamHandler) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := h.gotify.SubscribeMessages(r.Context(), r.PathValue("instance"), sinceFromQuery(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to subscribe to gotify messages: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for msg := range messages {
+		frame, err := services.MarshalGotifySSE(msg)
+		if err != nil {
+			continue
+		}
+
+		if _, err = w.Write(frame); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
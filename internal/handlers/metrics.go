@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"homepage-widgets-gateway/internal/metrics"
+	"net/http"
+)
+
+// MetricsHandler serves GET /metrics in Prometheus text format.
+func MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
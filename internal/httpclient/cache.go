@@ -0,0 +1,166 @@
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"homepage-widgets-gateway/internal/metrics"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultCacheMaxEntries bounds a cachingTransport's size when the caller
+// doesn't set Config.CacheMaxEntries, so an instance that's polled with a
+// constantly varying cache key (e.g. a paging cursor) can't grow the cache
+// forever.
+const defaultCacheMaxEntries = 500
+
+type cacheEntry struct {
+	key       string
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// cachingTransport caches successful GET responses for a short TTL, keyed by
+// method + URL + a hash of the caller-specified auth headers, so repeated
+// dashboard polls within the window reuse the previous response instead of
+// hitting the upstream service again. Entries are kept in an LRU list capped
+// at maxEntries, so a request pattern with a constantly changing cache key
+// evicts its oldest entries instead of growing the cache without bound.
+type cachingTransport struct {
+	next        http.RoundTripper
+	ttl         time.Duration
+	service     string
+	endpoint    func(*http.Request) string
+	authHeaders []string
+
+	maxEntries int
+
+	mu    sync.Mutex
+	order *list.List
+	index map[string]*list.Element
+}
+
+func newCachingTransport(next http.RoundTripper, ttl time.Duration, maxEntries int, service string, endpoint func(*http.Request) string, authHeaders []string) *cachingTransport {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+
+	return &cachingTransport{
+		next:        next,
+		ttl:         ttl,
+		service:     service,
+		endpoint:    endpoint,
+		authHeaders: authHeaders,
+		maxEntries:  maxEntries,
+		order:       list.New(),
+		index:       make(map[string]*list.Element),
+	}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req, t.authHeaders)
+
+	entry, ok := t.get(key)
+	if ok && time.Now().Before(entry.expiresAt) {
+		metrics.CacheHitsTotal.WithLabelValues(t.service, t.endpoint(req)).Inc()
+		return entry.response(req), nil
+	}
+
+	metrics.CacheMissesTotal.WithLabelValues(t.service, t.endpoint(req)).Inc()
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.put(cacheEntry{
+		key:       key,
+		status:    resp.StatusCode,
+		header:    resp.Header.Clone(),
+		body:      body,
+		expiresAt: time.Now().Add(t.ttl),
+	})
+
+	return resp, nil
+}
+
+// get returns the entry for key, if any, moving it to the front of the LRU
+// order since it was just used.
+func (t *cachingTransport) get(key string) (cacheEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, ok := t.index[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	t.order.MoveToFront(elem)
+	return elem.Value.(cacheEntry), true
+}
+
+// put inserts or refreshes entry at the front of the LRU order, evicting the
+// least-recently-used entry whenever the cache grows past maxEntries.
+func (t *cachingTransport) put(entry cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.index[entry.key]; ok {
+		elem.Value = entry
+		t.order.MoveToFront(elem)
+		return
+	}
+
+	t.index[entry.key] = t.order.PushFront(entry)
+
+	if t.order.Len() > t.maxEntries {
+		oldest := t.order.Back()
+		if oldest != nil {
+			t.order.Remove(oldest)
+			delete(t.index, oldest.Value.(cacheEntry).key)
+		}
+	}
+}
+
+func (e cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		StatusCode:    e.status,
+		Status:        http.StatusText(e.status),
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cacheKey hashes the method, URL, and the given header names, so two
+// requests for the same URL with different credentials (e.g. two Gotify
+// instances behind the same reverse proxy path) never share a cache entry.
+// authHeaders is caller-specified rather than hardcoded, since this package
+// is shared by every service client, not just Gotify.
+func cacheKey(req *http.Request, authHeaders []string) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte(req.URL.String()))
+	for _, name := range authHeaders {
+		h.Write([]byte(req.Header.Get(name)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// countingTransport counts how many times it's invoked, so tests can assert
+// whether a request was served from cache or passed through.
+type countingTransport struct {
+	calls int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     http.StatusText(http.StatusOK),
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func newTestRequest(url string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	return req
+}
+
+func TestCachingTransportEvictsLeastRecentlyUsed(t *testing.T) {
+	next := &countingTransport{}
+	transport := newCachingTransport(next, time.Hour, 2, "test", func(req *http.Request) string { return req.URL.Path }, nil)
+
+	req1 := newTestRequest("http://example.com/a")
+	req2 := newTestRequest("http://example.com/b")
+	req3 := newTestRequest("http://example.com/c")
+
+	if _, err := transport.RoundTrip(req1); err != nil {
+		t.Fatalf("req1: %v", err)
+	}
+	if _, err := transport.RoundTrip(req2); err != nil {
+		t.Fatalf("req2: %v", err)
+	}
+	if _, err := transport.RoundTrip(req3); err != nil {
+		t.Fatalf("req3: %v", err)
+	}
+
+	if len(transport.index) != 2 {
+		t.Fatalf("cache has %d entries, want 2 (max entries enforced)", len(transport.index))
+	}
+
+	if _, err := transport.RoundTrip(newTestRequest("http://example.com/a")); err != nil {
+		t.Fatalf("req1 replay: %v", err)
+	}
+	if next.calls != 4 {
+		t.Fatalf("next.calls = %d, want 4 (evicted entry should miss the cache)", next.calls)
+	}
+
+	if _, err := transport.RoundTrip(newTestRequest("http://example.com/c")); err != nil {
+		t.Fatalf("req3 replay: %v", err)
+	}
+	if next.calls != 4 {
+		t.Fatalf("next.calls = %d, want 4 (req3 should still be cached)", next.calls)
+	}
+}
+
+func TestCachingTransportSeparatesKeysByAuthHeader(t *testing.T) {
+	next := &countingTransport{}
+	transport := newCachingTransport(next, time.Hour, 10, "test", func(req *http.Request) string { return req.URL.Path }, []string{"X-Gotify-Key"})
+
+	reqA := newTestRequest("http://example.com/message")
+	reqA.Header.Set("X-Gotify-Key", "key-a")
+	reqB := newTestRequest("http://example.com/message")
+	reqB.Header.Set("X-Gotify-Key", "key-b")
+
+	if _, err := transport.RoundTrip(reqA); err != nil {
+		t.Fatalf("reqA: %v", err)
+	}
+	if _, err := transport.RoundTrip(reqB); err != nil {
+		t.Fatalf("reqB: %v", err)
+	}
+	if next.calls != 2 {
+		t.Fatalf("next.calls = %d, want 2 (same URL with different credentials must not share a cache entry)", next.calls)
+	}
+}
@@ -0,0 +1,24 @@
+package httpclient
+
+import "net/http"
+
+// concurrencyLimitedTransport bounds the number of in-flight requests so a
+// burst of simultaneous widget refreshes can't hammer the upstream service.
+type concurrencyLimitedTransport struct {
+	next http.RoundTripper
+	sem  chan struct{}
+}
+
+func newConcurrencyLimitedTransport(next http.RoundTripper, limit int) *concurrencyLimitedTransport {
+	return &concurrencyLimitedTransport{
+		next: next,
+		sem:  make(chan struct{}, limit),
+	}
+}
+
+func (t *concurrencyLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.sem <- struct{}{}
+	defer func() { <-t.sem }()
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryTransport retries requests that fail with a transient network error,
+// a 5xx status, or a 429, using jittered exponential backoff. A 429 honors
+// the upstream's Retry-After header when present.
+type retryTransport struct {
+	next        http.RoundTripper
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+func newRetryTransport(next http.RoundTripper, retryCount int, baseBackoff, maxBackoff time.Duration) *retryTransport {
+	if baseBackoff <= 0 {
+		baseBackoff = 200 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second
+	}
+
+	return &retryTransport{
+		next:        next,
+		maxAttempts: retryCount + 1,
+		baseBackoff: baseBackoff,
+		maxBackoff:  maxBackoff,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for attempt := 0; attempt < t.maxAttempts; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err = t.next.RoundTrip(req)
+		if !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		if attempt == t.maxAttempts-1 {
+			break
+		}
+
+		wait := retryAfter(resp)
+		if wait <= 0 {
+			wait = t.backoff(attempt)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return resp, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return resp, err
+}
+
+// shouldRetry reports whether a failed attempt is worth retrying. A request
+// whose context has already expired never is — retrying it just burns the
+// backoff budget on something that can't possibly succeed.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	backoff := t.baseBackoff << attempt
+	if backoff > t.maxBackoff || backoff <= 0 {
+		backoff = t.maxBackoff
+	}
+
+	// Full jitter to avoid synchronized retries across widget instances.
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	// Retry-After may also be an HTTP-date.
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package metrics instruments outbound requests made by service clients and
+// exposes them in Prometheus text format via Handler.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "service_requests_total",
+		Help: "Total outbound requests made to upstream services, by service, endpoint, and status code.",
+	}, []string{"service", "endpoint", "status"})
+
+	ErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "service_request_errors_total",
+		Help: "Total outbound requests that failed before receiving a response, by service and endpoint.",
+	}, []string{"service", "endpoint"})
+
+	InFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "service_requests_in_flight",
+		Help: "Outbound requests currently in flight, by service and endpoint.",
+	}, []string{"service", "endpoint"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "service_request_duration_seconds",
+		Help:    "Latency of outbound requests to upstream services.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "endpoint"})
+
+	ResponseBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "service_response_bytes",
+		Help:    "Size of responses received from upstream services.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"service", "endpoint"})
+
+	CacheHitsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "service_cache_hits_total",
+		Help: "Cache hits served without hitting the upstream service.",
+	}, []string{"service", "endpoint"})
+
+	CacheMissesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "service_cache_misses_total",
+		Help: "Cache misses that fell through to the upstream service.",
+	}, []string{"service", "endpoint"})
+
+	GotifyMessagesTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gotify_messages_total",
+		Help: "Total Gotify messages seen by the last GetMessages scan.",
+	})
+
+	GotifyPaginationPages = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gotify_pagination_pages",
+		Help: "Number of /message pages fetched by the last GetMessages scan.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsTotal,
+		ErrorsTotal,
+		InFlight,
+		RequestDuration,
+		ResponseBytes,
+		CacheHitsTotal,
+		CacheMissesTotal,
+		GotifyMessagesTotal,
+		GotifyPaginationPages,
+	)
+}
+
+// Handler serves the registered metrics in Prometheus text format on /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// InstrumentedTransport decorates an http.RoundTripper so every request it
+// makes is recorded as request count, error count, in-flight gauge, latency
+// histogram, and response size, without per-method boilerplate in the
+// calling service.
+type InstrumentedTransport struct {
+	Next     http.RoundTripper
+	Service  string
+	Endpoint func(*http.Request) string
+}
+
+func (t *InstrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := "unknown"
+	if t.Endpoint != nil {
+		endpoint = t.Endpoint(req)
+	}
+
+	InFlight.WithLabelValues(t.Service, endpoint).Inc()
+	defer InFlight.WithLabelValues(t.Service, endpoint).Dec()
+
+	start := time.Now()
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	RequestDuration.WithLabelValues(t.Service, endpoint).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		ErrorsTotal.WithLabelValues(t.Service, endpoint).Inc()
+		return resp, err
+	}
+
+	RequestsTotal.WithLabelValues(t.Service, endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+	ResponseBytes.WithLabelValues(t.Service, endpoint).Observe(float64(resp.ContentLength))
+
+	return resp, nil
+}
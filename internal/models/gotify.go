@@ -0,0 +1,23 @@
+package models
+
+// GotifyMessage is a single message as returned by Gotify's REST /message
+// endpoint and pushed over its /stream WebSocket.
+type GotifyMessage struct {
+	ID       int64                  `json:"id"`
+	AppID    int64                  `json:"appid"`
+	Message  string                 `json:"message"`
+	Title    string                 `json:"title"`
+	Priority int                    `json:"priority"`
+	Date     string                 `json:"date"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// GotifyMessageStats is the shape of a /message page: the messages on the
+// page plus Gotify's paging cursor for the next (older) page.
+type GotifyMessageStats struct {
+	Messages []GotifyMessage `json:"messages"`
+	Paging   struct {
+		Size  int `json:"size"`
+		Since int `json:"since"`
+	} `json:"paging"`
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errHTTP is a service error with a stable numeric code and the HTTP status
+// it should surface as, so callers can distinguish failure categories
+// (upstream down, bad credentials, unparsable response) instead of
+// collapsing everything into an opaque 500.
+type errHTTP struct {
+	Code       int
+	HTTPStatus int
+	Message    string
+	Link       string
+}
+
+func (e *errHTTP) Error() string {
+	return e.Message
+}
+
+var (
+	errGotifyUpstreamUnavailable = &errHTTP{
+		Code:       50201,
+		HTTPStatus: http.StatusBadGateway,
+		Message:    "gotify is unreachable",
+		Link:       "https://github.com/FuLygon/homepage-widgets-gateway/wiki/errors#50201",
+	}
+
+	errGotifyUnauthorized = &errHTTP{
+		Code:       40101,
+		HTTPStatus: http.StatusUnauthorized,
+		Message:    "gotify rejected the configured client key",
+		Link:       "https://github.com/FuLygon/homepage-widgets-gateway/wiki/errors#40101",
+	}
+
+	errGotifyParse = &errHTTP{
+		Code:       50202,
+		HTTPStatus: http.StatusBadGateway,
+		Message:    "failed to parse the gotify response",
+		Link:       "https://github.com/FuLygon/homepage-widgets-gateway/wiki/errors#50202",
+	}
+
+	errGotifyAllInstancesUnavailable = &errHTTP{
+		Code:       50203,
+		HTTPStatus: http.StatusBadGateway,
+		Message:    "all configured gotify instances failed",
+		Link:       "https://github.com/FuLygon/homepage-widgets-gateway/wiki/errors#50203",
+	}
+)
+
+// HTTPError is the shape the HTTP layer renders for a service error that
+// carries a stable code, e.g. {"code":50201,"http":502,"error":"...","link":"..."}.
+// It is also the shape stored per-instance in GotifyBreakdown.Errors, so a
+// fan-out failure carries the same structured code as a single-instance one.
+type HTTPError struct {
+	Code       int    `json:"code"`
+	HTTPStatus int    `json:"http"`
+	Message    string `json:"error"`
+	Link       string `json:"link,omitempty"`
+}
+
+// AsHTTPError unwraps err looking for an errHTTP sentinel, so handlers can
+// distinguish "gotify is down" from "token is wrong" from "we can't parse
+// the response" instead of collapsing everything into an opaque 500.
+func AsHTTPError(err error) (HTTPError, bool) {
+	var herr *errHTTP
+	if !errors.As(err, &herr) {
+		return HTTPError{}, false
+	}
+
+	return HTTPError{
+		Code:       herr.Code,
+		HTTPStatus: herr.HTTPStatus,
+		Message:    herr.Message,
+		Link:       herr.Link,
+	}, true
+}
+
+// toHTTPError is like AsHTTPError but never fails: errors that aren't an
+// errHTTP sentinel are given a generic bad-gateway shape instead of being
+// dropped, so every per-instance failure recorded in a GotifyBreakdown is
+// structured the same way.
+func toHTTPError(err error) HTTPError {
+	if herr, ok := AsHTTPError(err); ok {
+		return herr
+	}
+
+	return HTTPError{
+		HTTPStatus: http.StatusBadGateway,
+		Message:    err.Error(),
+	}
+}
+
+// wrapGotifyStatus maps a non-200 Gotify response to the matching errHTTP
+// sentinel, so a bad client key is distinguishable from a generally
+// unreachable/misbehaving Gotify instance.
+func wrapGotifyStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errGotifyUnauthorized
+	}
+
+	return errGotifyUpstreamUnavailable
+}
@@ -1,167 +1,332 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"homepage-widgets-gateway/config"
+	"homepage-widgets-gateway/internal/httpclient"
+	"homepage-widgets-gateway/internal/metrics"
 	"homepage-widgets-gateway/internal/models"
 	"net/http"
-	"net/url"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
+// gotifyFanOutTimeout bounds how long a single instance is given to answer
+// during an aggregated fan-out call, so one slow or broken Gotify instance
+// can't block the rest of the dashboard.
+const gotifyFanOutTimeout = 10 * time.Second
+
+// GotifyBreakdown is the result of fanning a call out across every
+// configured Gotify instance: a merged total plus a per-instance count, with
+// any per-instance failures reported alongside rather than failing the
+// whole call.
+type GotifyBreakdown struct {
+	Total     int                  `json:"total"`
+	Instances map[string]int       `json:"instances"`
+	Errors    map[string]HTTPError `json:"errors,omitempty"`
+}
+
 type GotifyService interface {
-	GetMessages() (map[string]interface{}, error)
-	GetApplications() (interface{}, error)
-	GetClients() (interface{}, error)
+	// GetApplications, GetClients, and GetMessages fan out across every
+	// configured instance and return the merged total plus a per-instance
+	// breakdown.
+	GetApplications() (GotifyBreakdown, error)
+	GetClients() (GotifyBreakdown, error)
+	GetMessages() (GotifyBreakdown, error)
+
+	// The *ForInstance variants target a single named instance and return
+	// the same shape GetApplications/GetClients/GetMessages used to before
+	// this service supported more than one instance.
+	GetApplicationsForInstance(instance string) (interface{}, error)
+	GetClientsForInstance(instance string) (interface{}, error)
+	GetMessagesForInstance(instance string) (map[string]interface{}, error)
+
+	// SubscribeMessages streams decoded Gotify messages from a single named
+	// instance to the caller, rather than re-polling GetMessagesForInstance.
+	// If since is non-zero, the caller is first backfilled with any
+	// recently broadcast messages newer than since.
+	SubscribeMessages(ctx context.Context, instance string, since int64) (<-chan models.GotifyMessage, error)
+
+	// CountMessagesSince walks from the newest page back to the given message
+	// ID and returns how many messages have arrived since, without touching
+	// the instance's cached high-water mark.
+	CountMessagesSince(ctx context.Context, instance string, since int64) (int, error)
 }
 
-type gotifyService struct {
+type gotifyInstance struct {
 	client  *http.Client
 	baseUrl string
 	key     string
+
+	hub     *gotifyHub
+	hubOnce sync.Once
+
+	counter *gotifyCounter
+}
+
+type gotifyService struct {
+	instances map[string]*gotifyInstance
 }
 
 func NewGotifyService(serviceConfig config.ServicesConfig) GotifyService {
-	baseConfig := serviceConfig.Gotify
-	return &gotifyService{
-		client: &http.Client{
+	instances := make(map[string]*gotifyInstance, len(serviceConfig.Gotify))
+	for name, instanceConfig := range serviceConfig.Gotify {
+		client := httpclient.New(&http.Client{
 			Timeout: 10 * time.Second,
-		},
-		baseUrl: baseConfig.Url,
-		key:     baseConfig.Key,
+		}, httpclient.Config{
+			CacheTTL:         instanceConfig.CacheTTL,
+			CacheKeyHeaders:  []string{"X-Gotify-Key"},
+			RetryCount:       instanceConfig.RetryCount,
+			RetryBaseBackoff: instanceConfig.RetryBackoff,
+			RetryMaxBackoff:  instanceConfig.RetryBackoff * 10,
+			ConcurrencyLimit: instanceConfig.ConcurrencyLimit,
+			Service:          "gotify",
+			Endpoint: func(req *http.Request) string {
+				return fmt.Sprintf("%s:%s", name, req.URL.Path)
+			},
+		})
+
+		instances[name] = &gotifyInstance{
+			client:  client,
+			baseUrl: instanceConfig.Url,
+			key:     instanceConfig.Key,
+			counter: newGotifyCounter(instanceConfig.StatePath),
+		}
+	}
+
+	return &gotifyService{instances: instances}
+}
+
+func (s *gotifyService) instance(name string) (*gotifyInstance, error) {
+	instance, ok := s.instances[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown gotify instance %q", name)
+	}
+
+	return instance, nil
+}
+
+// fanOut runs call against every configured instance concurrently via
+// errgroup, each bounded by gotifyFanOutTimeout, and merges the per-instance
+// counts into a GotifyBreakdown. A failing instance is recorded in Errors
+// as a structured HTTPError rather than failing the whole call, and only if
+// every instance fails does fanOut itself return an error.
+func (s *gotifyService) fanOut(call func(context.Context, *gotifyInstance) (int, error)) (GotifyBreakdown, error) {
+	breakdown := GotifyBreakdown{
+		Instances: make(map[string]int, len(s.instances)),
+	}
+
+	var (
+		mu sync.Mutex
+		g  errgroup.Group
+	)
+
+	for name, instance := range s.instances {
+		name, instance := name, instance
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), gotifyFanOutTimeout)
+			defer cancel()
+
+			count, err := call(ctx, instance)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if breakdown.Errors == nil {
+					breakdown.Errors = make(map[string]HTTPError)
+				}
+				breakdown.Errors[name] = toHTTPError(err)
+				return nil
+			}
+
+			breakdown.Instances[name] = count
+			breakdown.Total += count
+			return nil
+		})
+	}
+
+	// call never returns an error from the group itself; failures are
+	// captured per-instance above so one broken instance never aborts the
+	// others.
+	_ = g.Wait()
+
+	if len(breakdown.Errors) == len(s.instances) && len(s.instances) > 0 {
+		return breakdown, errGotifyAllInstancesUnavailable
 	}
+
+	return breakdown, nil
 }
 
 // GetApplications implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
-func (s *gotifyService) GetApplications() (interface{}, error) {
+func (s *gotifyService) GetApplications() (GotifyBreakdown, error) {
+	return s.fanOut(func(ctx context.Context, instance *gotifyInstance) (int, error) {
+		count, _, err := instance.getApplications(ctx)
+		return count, err
+	})
+}
+
+// GetClients implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
+func (s *gotifyService) GetClients() (GotifyBreakdown, error) {
+	return s.fanOut(func(ctx context.Context, instance *gotifyInstance) (int, error) {
+		count, _, err := instance.getClients(ctx)
+		return count, err
+	})
+}
+
+// GetMessages partially implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
+// Because the current implementation by Homepage has an issue where messages are capped at 100
+func (s *gotifyService) GetMessages() (GotifyBreakdown, error) {
+	return s.fanOut(func(ctx context.Context, instance *gotifyInstance) (int, error) {
+		return instance.getMessages(ctx)
+	})
+}
+
+func (s *gotifyService) GetApplicationsForInstance(name string) (interface{}, error) {
+	instance, err := s.instance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, response, err := instance.getApplications(context.Background())
+	return response, err
+}
+
+func (s *gotifyService) GetClientsForInstance(name string) (interface{}, error) {
+	instance, err := s.instance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, response, err := instance.getClients(context.Background())
+	return response, err
+}
+
+func (s *gotifyService) GetMessagesForInstance(name string) (map[string]interface{}, error) {
+	instance, err := s.instance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	totalMessages, err := instance.getMessages(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a fake response with the same length as totalMessages
+	messages := make([]struct{}, totalMessages)
+	response := make(map[string]interface{})
+	response["messages"] = messages
+
+	return response, nil
+}
+
+func (s *gotifyService) SubscribeMessages(ctx context.Context, name string, since int64) (<-chan models.GotifyMessage, error) {
+	instance, err := s.instance(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.subscribeMessages(ctx, since)
+}
+
+func (s *gotifyService) CountMessagesSince(ctx context.Context, name string, since int64) (int, error) {
+	instance, err := s.instance(name)
+	if err != nil {
+		return 0, err
+	}
+
+	count, _, err := instance.countMessagesSince(ctx, since)
+	return count, err
+}
+
+func (i *gotifyInstance) getApplications(ctx context.Context) (int, []struct{}, error) {
 	// Prepare stats request
-	applicationStatsReq, err := http.NewRequest("GET", fmt.Sprintf("%s/application", s.baseUrl), nil)
+	applicationStatsReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/application", i.baseUrl), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare application stats request: %w", err)
+		return 0, nil, fmt.Errorf("failed to prepare application stats request: %w", err)
 	}
 
-	applicationStatsReq.Header.Add("X-Gotify-Key", s.key)
+	applicationStatsReq.Header.Add("X-Gotify-Key", i.key)
 
 	// Make stats request
-	resp, err := s.client.Do(applicationStatsReq)
+	resp, err := i.client.Do(applicationStatsReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch application stats: %w", err)
+		return 0, nil, fmt.Errorf("failed to fetch application stats: %v: %w", err, errGotifyUpstreamUnavailable)
 	}
 	defer resp.Body.Close()
 
 	// Return error if status code is not 200
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch application stats with status: %s", resp.Status)
+		return 0, nil, fmt.Errorf("failed to fetch application stats with status %s: %w", resp.Status, wrapGotifyStatus(resp))
 	}
 
 	// Parse stats response
 	var applicationsStats []map[string]interface{}
 	if err = json.NewDecoder(resp.Body).Decode(&applicationsStats); err != nil {
-		return nil, fmt.Errorf("failed to parse application stats response: %w", err)
+		return 0, nil, fmt.Errorf("failed to parse application stats response: %v: %w", err, errGotifyParse)
 	}
 
 	// Create a fake response with the same length as applicationsStats
 	response := make([]struct{}, len(applicationsStats))
-	return response, nil
+	return len(response), response, nil
 }
 
-// GetClients implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
-func (s *gotifyService) GetClients() (interface{}, error) {
+func (i *gotifyInstance) getClients(ctx context.Context) (int, []struct{}, error) {
 	// Prepare stats request
-	clientStatsReq, err := http.NewRequest("GET", fmt.Sprintf("%s/client", s.baseUrl), nil)
+	clientStatsReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/client", i.baseUrl), nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to prepare client stats request: %w", err)
+		return 0, nil, fmt.Errorf("failed to prepare client stats request: %w", err)
 	}
 
-	clientStatsReq.Header.Add("X-Gotify-Key", s.key)
+	clientStatsReq.Header.Add("X-Gotify-Key", i.key)
 
 	// Make stats request
-	resp, err := s.client.Do(clientStatsReq)
+	resp, err := i.client.Do(clientStatsReq)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch client stats: %w", err)
+		return 0, nil, fmt.Errorf("failed to fetch client stats: %v: %w", err, errGotifyUpstreamUnavailable)
 	}
 	defer resp.Body.Close()
 
 	// Return error if status code is not 200
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to fetch client stats with status: %s", resp.Status)
+		return 0, nil, fmt.Errorf("failed to fetch client stats with status %s: %w", resp.Status, wrapGotifyStatus(resp))
 	}
 
 	// Parse stats response
 	var clientsStats []map[string]interface{}
 	if err = json.NewDecoder(resp.Body).Decode(&clientsStats); err != nil {
-		return 0, fmt.Errorf("failed to parse client stats response: %w", err)
+		return 0, nil, fmt.Errorf("failed to parse client stats response: %v: %w", err, errGotifyParse)
 	}
 
 	// Create a fake response with the same length as clientsStats
 	response := make([]struct{}, len(clientsStats))
-	return response, nil
+	return len(response), response, nil
 }
 
-// GetMessages partially implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
-// Because the current implementation by Homepage has an issue where messages are capped at 100
-func (s *gotifyService) GetMessages() (map[string]interface{}, error) {
-	var (
-		totalMessages int
-		offset        int
-	)
-	for {
-		size, since, err := func() (int, int, error) {
-			// Prepare stats request
-			reqUrl, err := url.Parse(fmt.Sprintf("%s/message", s.baseUrl))
-			if err != nil {
-				return 0, 0, fmt.Errorf("failed to parse message stats request URL: %w", err)
-			}
-
-			queryParams := reqUrl.Query()
-			queryParams.Set("limit", "200") // Limitation by Gotify API
-			queryParams.Set("since", fmt.Sprintf("%d", offset))
-			reqUrl.RawQuery = queryParams.Encode()
-
-			clientStatsReq, err := http.NewRequest("GET", reqUrl.String(), nil)
-			if err != nil {
-				return 0, 0, fmt.Errorf("failed to prepare message stats request: %w", err)
-			}
-
-			clientStatsReq.Header.Add("X-Gotify-Key", s.key)
-
-			// Make stats request
-			resp, err := s.client.Do(clientStatsReq)
-			if err != nil {
-				return 0, 0, fmt.Errorf("failed to fetch message stats: %w", err)
-			}
-			defer resp.Body.Close()
+// getMessages partially implement from https://github.com/gethomepage/homepage/blob/main/src/widgets/gotify/component.jsx
+// Because the current implementation by Homepage has an issue where messages are capped at 100.
+//
+// Rather than re-paginating the whole /message history on every call, it
+// walks from the newest page back only as far as the instance's cached
+// high-water mark, so a typical call after the first full scan is usually
+// one request.
+func (i *gotifyInstance) getMessages(ctx context.Context) (int, error) {
+	previous := i.counter.snapshot()
 
-			// Return error if status code is not 200
-			if resp.StatusCode != http.StatusOK {
-				return 0, 0, fmt.Errorf("failed to fetch message stats with status: %s", resp.Status)
-			}
-
-			// Parse stats response
-			var messageStats models.GotifyMessageStats
-			if err = json.NewDecoder(resp.Body).Decode(&messageStats); err != nil {
-				return 0, 0, fmt.Errorf("failed to parse message stats response: %w", err)
-			}
-
-			return messageStats.Paging.Size, messageStats.Paging.Since, nil
-		}()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get total messages: %w", err)
-		}
-
-		totalMessages += size
-		if since == 0 {
-			break
-		} else {
-			offset = since
-		}
+	delta, lastID, err := i.countMessagesSince(ctx, previous.LastID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get total messages: %w", err)
 	}
 
-	// Create a fake response with the same length as totalMessages
-	messages := make([]struct{}, totalMessages)
-	response := make(map[string]interface{})
-	response["messages"] = messages
+	i.counter.advance(lastID, int64(delta))
+	total := i.counter.snapshot().Total
 
-	return response, nil
+	metrics.GotifyMessagesTotal.Set(float64(total))
+
+	return int(total), nil
 }
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"homepage-widgets-gateway/internal/metrics"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// gotifyMaxPages caps how many /message pages a single counting pass will
+// walk, so a misconfigured Gotify URL that never returns since=0 can't spin
+// the loop forever.
+const gotifyMaxPages = 500
+
+// gotifyCounterState is the high-water mark persisted for an instance: the
+// highest message ID it has accounted for, and the running total as of that
+// ID. Once populated, subsequent counts only need to walk the newest page(s)
+// until they reach that ID instead of rescanning the whole history.
+type gotifyCounterState struct {
+	LastID int64 `json:"lastId"`
+	Total  int64 `json:"total"`
+}
+
+// gotifyCounter tracks the high-water mark for one instance in memory, and
+// mirrors it to disk when a state path is configured so the mark survives a
+// restart.
+type gotifyCounter struct {
+	mu        sync.Mutex
+	state     gotifyCounterState
+	statePath string
+}
+
+func newGotifyCounter(statePath string) *gotifyCounter {
+	c := &gotifyCounter{statePath: statePath}
+	c.load()
+	return c
+}
+
+func (c *gotifyCounter) load() {
+	if c.statePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.statePath)
+	if err != nil {
+		return
+	}
+
+	_ = json.Unmarshal(data, &c.state)
+}
+
+func (c *gotifyCounter) save() {
+	if c.statePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(c.statePath, data, 0o644)
+}
+
+func (c *gotifyCounter) snapshot() gotifyCounterState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+func (c *gotifyCounter) advance(lastID int64, delta int64) {
+	c.mu.Lock()
+	if lastID > c.state.LastID {
+		c.state.LastID = lastID
+	}
+	c.state.Total += delta
+	c.mu.Unlock()
+
+	c.save()
+}
+
+// countMessagesSince walks Gotify's /message pages, newest first, and
+// returns how many messages have an ID greater than highWaterMark plus the
+// highest message ID seen. Gotify's `since` query parameter is a paging
+// cursor, not a lower bound: `/message?since=X` returns messages with an ID
+// *less than* X, walking backward toward the beginning of history. So to
+// find messages newer than highWaterMark, paging always starts at since=0
+// (the newest page) and walks backward only until a page's messages drop to
+// or below highWaterMark — at that point everything older has already been
+// counted, so the walk stops without rescanning the rest of the history.
+//
+// highWaterMark of 0 performs a full scan (every message is "new").
+// Stops after gotifyMaxPages pages so a misconfigured or misbehaving Gotify
+// instance can't loop forever, and the request is context-cancellable so a
+// caller can bound the whole walk.
+func (i *gotifyInstance) countMessagesSince(ctx context.Context, highWaterMark int64) (int, int64, error) {
+	var (
+		count   int
+		newHigh = highWaterMark
+		cursor  int64
+	)
+
+	for page := 0; page < gotifyMaxPages; page++ {
+		messages, pagingSince, err := i.fetchMessagePage(ctx, cursor)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if page == 0 {
+			for _, id := range messages {
+				if id > newHigh {
+					newHigh = id
+				}
+			}
+		}
+
+		reachedKnownMessages := false
+		for _, id := range messages {
+			if id > highWaterMark {
+				count++
+			} else {
+				reachedKnownMessages = true
+			}
+		}
+
+		if reachedKnownMessages || pagingSince == 0 {
+			metrics.GotifyPaginationPages.Set(float64(page + 1))
+			return count, newHigh, nil
+		}
+		cursor = int64(pagingSince)
+	}
+
+	return 0, 0, fmt.Errorf("exceeded max pages (%d) counting gotify messages since %d", gotifyMaxPages, highWaterMark)
+}
+
+// fetchMessagePage fetches one page of /message starting at cursor (0 means
+// the newest messages) and returns the IDs on that page along with Gotify's
+// paging.since cursor for the next (older) page, or 0 if there is none.
+func (i *gotifyInstance) fetchMessagePage(ctx context.Context, cursor int64) (ids []int64, nextSince int, err error) {
+	reqUrl, err := url.Parse(fmt.Sprintf("%s/message", i.baseUrl))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse message stats request URL: %w", err)
+	}
+
+	queryParams := reqUrl.Query()
+	queryParams.Set("limit", "200") // Limitation by Gotify API
+	queryParams.Set("since", fmt.Sprintf("%d", cursor))
+	reqUrl.RawQuery = queryParams.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqUrl.String(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to prepare message stats request: %w", err)
+	}
+
+	req.Header.Add("X-Gotify-Key", i.key)
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch message stats: %v: %w", err, errGotifyUpstreamUnavailable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch message stats with status %s: %w", resp.Status, wrapGotifyStatus(resp))
+	}
+
+	var messageStats struct {
+		Messages []struct {
+			ID int64 `json:"id"`
+		} `json:"messages"`
+		Paging struct {
+			Since int `json:"since"`
+		} `json:"paging"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&messageStats); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse message stats response: %v: %w", err, errGotifyParse)
+	}
+
+	ids = make([]int64, len(messageStats.Messages))
+	for idx, msg := range messageStats.Messages {
+		ids[idx] = msg.ID
+	}
+
+	return ids, messageStats.Paging.Since, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// fakeGotifyMessageTransport simulates Gotify's /message endpoint over a
+// fixed, descending-by-ID message history, paging backward the same way the
+// real API does: `since` is an exclusive upper bound, and the response's
+// paging.since is the cursor for the next (older) page, or 0 once the
+// oldest message has been returned.
+type fakeGotifyMessageTransport struct {
+	ids      []int64 // ascending
+	pageSize int
+}
+
+func (t *fakeGotifyMessageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var since int64
+	_, _ = fmt.Sscanf(req.URL.Query().Get("since"), "%d", &since)
+
+	pageSize := t.pageSize
+	if pageSize == 0 {
+		pageSize = 200
+	}
+
+	var page []int64
+	for i := len(t.ids) - 1; i >= 0; i-- {
+		id := t.ids[i]
+		if since != 0 && id >= since {
+			continue
+		}
+		page = append(page, id)
+		if len(page) == pageSize {
+			break
+		}
+	}
+
+	nextSince := 0
+	if len(page) > 0 {
+		oldestOnPage := page[len(page)-1]
+		for _, id := range t.ids {
+			if id < oldestOnPage {
+				nextSince = int(oldestOnPage)
+				break
+			}
+		}
+	}
+
+	type message struct {
+		ID int64 `json:"id"`
+	}
+	body := struct {
+		Messages []message `json:"messages"`
+		Paging   struct {
+			Since int `json:"since"`
+		} `json:"paging"`
+	}{}
+	for _, id := range page {
+		body.Messages = append(body.Messages, message{ID: id})
+	}
+	body.Paging.Since = nextSince
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     http.StatusText(http.StatusOK),
+		Body:       io.NopCloser(bytes.NewReader(payload)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func newTestGotifyInstance(ids []int64) *gotifyInstance {
+	return &gotifyInstance{
+		client:  &http.Client{Transport: &fakeGotifyMessageTransport{ids: ids}},
+		baseUrl: "http://gotify.example",
+		key:     "test-key",
+		counter: newGotifyCounter(""),
+	}
+}
+
+func TestGetMessagesDoesNotGrowWithoutNewMessages(t *testing.T) {
+	instance := newTestGotifyInstance([]int64{1, 2, 3})
+
+	first, err := instance.getMessages(context.Background())
+	if err != nil {
+		t.Fatalf("first getMessages: %v", err)
+	}
+	if first != 3 {
+		t.Fatalf("first getMessages = %d, want 3", first)
+	}
+
+	for i := 0; i < 3; i++ {
+		again, err := instance.getMessages(context.Background())
+		if err != nil {
+			t.Fatalf("repeat getMessages: %v", err)
+		}
+		if again != 3 {
+			t.Fatalf("repeat getMessages = %d, want 3 (count must not grow without new upstream messages)", again)
+		}
+	}
+}
+
+func TestGetMessagesCountsOnlyNewMessages(t *testing.T) {
+	transport := &fakeGotifyMessageTransport{ids: []int64{1, 2, 3}}
+	instance := &gotifyInstance{
+		client:  &http.Client{Transport: transport},
+		baseUrl: "http://gotify.example",
+		key:     "test-key",
+		counter: newGotifyCounter(""),
+	}
+
+	total, err := instance.getMessages(context.Background())
+	if err != nil {
+		t.Fatalf("initial getMessages: %v", err)
+	}
+	if total != 3 {
+		t.Fatalf("initial getMessages = %d, want 3", total)
+	}
+
+	transport.ids = append(transport.ids, 4, 5)
+
+	total, err = instance.getMessages(context.Background())
+	if err != nil {
+		t.Fatalf("getMessages after new messages: %v", err)
+	}
+	if total != 5 {
+		t.Fatalf("getMessages after new messages = %d, want 5", total)
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// fakeStatusTransport returns a fixed status and JSON body for every
+// request, regardless of path or query — enough to exercise fanOut's
+// success and failure paths without a real Gotify instance.
+type fakeStatusTransport struct {
+	status int
+	body   string
+}
+
+func (t *fakeStatusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: t.status,
+		Status:     http.StatusText(t.status),
+		Body:       io.NopCloser(bytes.NewReader([]byte(t.body))),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func newTestGotifyService(instances map[string]*gotifyInstance) *gotifyService {
+	return &gotifyService{instances: instances}
+}
+
+func TestFanOutPartialFailure(t *testing.T) {
+	service := newTestGotifyService(map[string]*gotifyInstance{
+		"ok": {
+			client:  &http.Client{Transport: &fakeStatusTransport{status: http.StatusOK, body: `[{},{}]`}},
+			baseUrl: "http://ok.example",
+			key:     "ok-key",
+			counter: newGotifyCounter(""),
+		},
+		"down": {
+			client:  &http.Client{Transport: &fakeStatusTransport{status: http.StatusUnauthorized, body: `{}`}},
+			baseUrl: "http://down.example",
+			key:     "bad-key",
+			counter: newGotifyCounter(""),
+		},
+	})
+
+	breakdown, err := service.GetApplications()
+	if err != nil {
+		t.Fatalf("GetApplications: %v", err)
+	}
+
+	if breakdown.Total != 2 {
+		t.Fatalf("breakdown.Total = %d, want 2", breakdown.Total)
+	}
+	if breakdown.Instances["ok"] != 2 {
+		t.Fatalf("breakdown.Instances[ok] = %d, want 2", breakdown.Instances["ok"])
+	}
+
+	herr, ok := breakdown.Errors["down"]
+	if !ok {
+		t.Fatalf("breakdown.Errors missing entry for the failing instance")
+	}
+	if herr.Code != errGotifyUnauthorized.Code || herr.HTTPStatus != http.StatusUnauthorized {
+		t.Fatalf("breakdown.Errors[down] = %+v, want code %d / status %d", herr, errGotifyUnauthorized.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestFanOutAllInstancesFailing(t *testing.T) {
+	service := newTestGotifyService(map[string]*gotifyInstance{
+		"down": {
+			client:  &http.Client{Transport: &fakeStatusTransport{status: http.StatusUnauthorized, body: `{}`}},
+			baseUrl: "http://down.example",
+			key:     "bad-key",
+			counter: newGotifyCounter(""),
+		},
+	})
+
+	breakdown, err := service.GetApplications()
+	if err == nil {
+		t.Fatal("GetApplications: expected an error when every instance fails")
+	}
+
+	herr, ok := AsHTTPError(err)
+	if !ok {
+		t.Fatalf("GetApplications error is not a structured HTTPError: %v", err)
+	}
+	if herr.Code != errGotifyAllInstancesUnavailable.Code {
+		t.Fatalf("error code = %d, want %d", herr.Code, errGotifyAllInstancesUnavailable.Code)
+	}
+
+	if len(breakdown.Errors) != 1 {
+		t.Fatalf("breakdown.Errors = %+v, want exactly the one failing instance", breakdown.Errors)
+	}
+}
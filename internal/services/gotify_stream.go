@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"homepage-widgets-gateway/internal/models"
+	"log"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// gotifyStreamKeepaliveInterval is how often we ping the upstream Gotify
+	// WebSocket to keep reverse proxies from closing the connection as idle.
+	gotifyStreamKeepaliveInterval = 45 * time.Second
+
+	gotifyStreamMinBackoff = 1 * time.Second
+	gotifyStreamMaxBackoff = 30 * time.Second
+
+	// gotifyHubBacklogSize bounds how many recent messages the hub retains
+	// for replay, so a client that reconnects with a `since` can backfill
+	// what it missed without the backlog growing unbounded.
+	gotifyHubBacklogSize = 200
+)
+
+// gotifyHub multiplexes a single upstream Gotify /stream WebSocket connection
+// across any number of downstream subscribers, and retains a bounded backlog
+// of recent messages so a reconnecting subscriber can backfill via `since`.
+type gotifyHub struct {
+	mu          sync.Mutex
+	subscribers map[chan models.GotifyMessage]struct{}
+	backlog     []models.GotifyMessage
+	lastMsgID   int64
+}
+
+func newGotifyHub() *gotifyHub {
+	return &gotifyHub{
+		subscribers: make(map[chan models.GotifyMessage]struct{}),
+	}
+}
+
+// subscribe registers a new subscriber and, if since is non-zero, seeds its
+// channel with any backlogged messages newer than since before the channel
+// starts receiving live broadcasts.
+func (h *gotifyHub) subscribe(since int64) chan models.GotifyMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backfill := 0
+	if since > 0 {
+		for _, msg := range h.backlog {
+			if msg.ID > since {
+				backfill++
+			}
+		}
+	}
+
+	ch := make(chan models.GotifyMessage, 16+backfill)
+
+	if since > 0 {
+		for _, msg := range h.backlog {
+			if msg.ID > since {
+				ch <- msg
+			}
+		}
+	}
+
+	h.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (h *gotifyHub) unsubscribe(ch chan models.GotifyMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// lastID returns the ID of the most recently broadcast message, or 0 if
+// none has been broadcast yet.
+func (h *gotifyHub) lastID() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastMsgID
+}
+
+func (h *gotifyHub) broadcast(msg models.GotifyMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastMsgID = msg.ID
+
+	h.backlog = append(h.backlog, msg)
+	if len(h.backlog) > gotifyHubBacklogSize {
+		h.backlog = h.backlog[len(h.backlog)-gotifyHubBacklogSize:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			// Slow subscriber, drop the message rather than block the hub.
+		}
+	}
+}
+
+// subscribeMessages opens (or reuses) a persistent connection to this
+// instance's Gotify /stream endpoint and returns a channel of decoded
+// messages for the caller. The upstream connection reconnects with
+// exponential backoff on drop and is shared across all subscribers returned
+// by this method. If since is non-zero, the caller is first backfilled with
+// any backlogged messages newer than since, so a client that reconnects
+// doesn't miss messages that arrived while it was disconnected.
+func (i *gotifyInstance) subscribeMessages(ctx context.Context, since int64) (<-chan models.GotifyMessage, error) {
+	i.hubOnce.Do(func() {
+		i.hub = newGotifyHub()
+		go i.runGotifyStream(context.Background())
+	})
+
+	ch := i.hub.subscribe(since)
+
+	go func() {
+		<-ctx.Done()
+		i.hub.unsubscribe(ch)
+	}()
+
+	return ch, nil
+}
+
+// runGotifyStream owns the single upstream Gotify WebSocket connection and
+// keeps it alive for the lifetime of the service, reconnecting on failure
+// with exponential backoff and backfilling any messages missed while
+// disconnected via the `since` query parameter.
+func (i *gotifyInstance) runGotifyStream(ctx context.Context) {
+	backoff := gotifyStreamMinBackoff
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		since := i.hub.lastID()
+		if err := i.connectGotifyStream(ctx, since); err != nil {
+			log.Printf("gotify stream: %v, reconnecting in %s", err, backoff)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = nextGotifyBackoff(backoff)
+			continue
+		}
+
+		// Connection closed cleanly, reset backoff and retry immediately.
+		backoff = gotifyStreamMinBackoff
+	}
+}
+
+func nextGotifyBackoff(prev time.Duration) time.Duration {
+	next := prev * 2
+	if next > gotifyStreamMaxBackoff {
+		next = gotifyStreamMaxBackoff
+	}
+
+	// Add jitter so many widget instances don't reconnect in lockstep.
+	jitter := time.Duration(rand.Int63n(int64(next) / 2))
+	return next/2 + jitter
+}
+
+func (i *gotifyInstance) connectGotifyStream(ctx context.Context, since int64) error {
+	streamUrl, err := url.Parse(fmt.Sprintf("%s/stream", i.baseUrl))
+	if err != nil {
+		return fmt.Errorf("failed to parse stream URL: %w", err)
+	}
+
+	switch streamUrl.Scheme {
+	case "https":
+		streamUrl.Scheme = "wss"
+	default:
+		streamUrl.Scheme = "ws"
+	}
+
+	queryParams := streamUrl.Query()
+	queryParams.Set("token", i.key)
+	if since > 0 {
+		queryParams.Set("since", strconv.FormatInt(since, 10))
+	}
+	streamUrl.RawQuery = queryParams.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, streamUrl.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial gotify stream: %w", err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	go i.pingGotifyStream(ctx, conn, done)
+	defer close(done)
+
+	for {
+		var msg models.GotifyMessage
+		if err = conn.ReadJSON(&msg); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return fmt.Errorf("gotify stream read failed: %w", err)
+		}
+
+		i.hub.broadcast(msg)
+	}
+}
+
+func (i *gotifyInstance) pingGotifyStream(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(gotifyStreamKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// MarshalGotifySSE encodes a Gotify message as a single Server-Sent Events frame.
+func MarshalGotifySSE(msg models.GotifyMessage) ([]byte, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message for SSE: %w", err)
+	}
+
+	return append(append([]byte("data: "), payload...), '\n', '\n'), nil
+}